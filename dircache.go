@@ -0,0 +1,24 @@
+package main
+
+import "sync"
+
+// dirCache caches folder lookups/creates keyed by "parentId/name" -> folderId
+// and serializes concurrent access to createDriveDirectory, so upload workers
+// under mirrorDirectoryStructureInput don't race to create the same folder
+// twice. This mirrors rclone's lib/dircache design.
+type dirCache struct {
+	mu       sync.Mutex
+	entries  map[string]string
+	disabled bool
+}
+
+func newDirCache(disabled bool) *dirCache {
+	return &dirCache{
+		entries:  make(map[string]string),
+		disabled: disabled,
+	}
+}
+
+func dirCacheKey(parentId string, name string) string {
+	return parentId + "/" + name
+}
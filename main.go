@@ -10,15 +10,23 @@ package main
 
 import (
 	"context"
+	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sethvargo/go-githubactions"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
@@ -32,9 +40,36 @@ const (
 	useCompleteSourceName         = "useCompleteSourceFilenameAsName"
 	mirrorDirectoryStructureInput = "mirrorDirectoryStructureInput"
 	namePrefixInput               = "namePrefix"
+	chunkSizeInput                = "chunkSize"
+	driveIdInput                  = "driveId"
+	maxRetriesInput               = "maxRetries"
+	minSleepInput                 = "minSleep"
+	skipIfUnchangedInput          = "skipIfUnchanged"
+	concurrencyInput              = "concurrency"
+	noDirCacheInput               = "noDirCache"
+	importAsGoogleDocInput        = "importAsGoogleDoc"
+	exportMimeTypeInput           = "exportMimeType"
+	oauthClientIdInput            = "oauthClientId"
+	oauthClientSecretInput        = "oauthClientSecret"
+	oauthRefreshTokenInput        = "oauthRefreshToken"
 )
 
-func uploadToDrive(svc *drive.Service, filename string, folderId string, driveFile *drive.File, name string, mimeType string) error {
+// defaultConcurrency is the number of upload workers used when
+// concurrencyInput isn't set.
+const defaultConcurrency = 4
+
+// defaultChunkSize is the resumable upload chunk size used when chunkSizeInput
+// isn't set, matching rclone's Drive backend default.
+const defaultChunkSize = 8 * 1024 * 1024 // 8 MiB
+
+// Defaults for the pacer, matching rclone's lib/pacer Drive backend settings.
+const (
+	defaultMaxRetries = 10
+	defaultMinSleep   = 10 * time.Millisecond
+	pacerMaxSleep     = 2 * time.Second
+)
+
+func uploadToDrive(p *pacer, svc *drive.Service, filename string, folderId string, driveFile *drive.File, name string, mimeType string, chunkSize int, skipIfUnchanged bool, importAsGoogleDoc bool, exportMimeType string) error {
 	fi, err := os.Lstat(filename)
 	if err != nil {
 		return fmt.Errorf("unable to stat file: %w", err)
@@ -45,24 +80,69 @@ func uploadToDrive(svc *drive.Service, filename string, folderId string, driveFi
 		return nil
 	}
 
+	if driveFile != nil && skipIfUnchanged {
+		unchanged, err := fileUnchangedOnDrive(filename, fi, driveFile)
+		if err != nil {
+			return err
+		}
+
+		if unchanged {
+			githubactions.Infof("%s is unchanged, skipping upload.", filename)
+			return nil
+		}
+	}
+
 	file, err := os.Open(filename)
 	if err != nil {
 		return fmt.Errorf("opening file with filename: %s failed with error: %w", filename, err)
 	}
 
+	// Files smaller than one chunk don't benefit from a resumable upload, so
+	// fall back to a simple, single-request upload.
+	effectiveChunkSize := chunkSize
+	if fi.Size() < int64(chunkSize) {
+		effectiveChunkSize = 0
+	}
+
+	mediaOption := googleapi.ChunkSize(effectiveChunkSize)
+	progressUpdater := newUploadProgressUpdater(filename)
+
+	targetMimeType := mimeType
+	if importAsGoogleDoc {
+		targetMimeType = googleDocMimeTypeForFile(filename, exportMimeType)
+	}
+
 	if driveFile != nil {
 		f := &drive.File{
 			Name:     name,
-			MimeType: mimeType,
+			MimeType: targetMimeType,
 		}
-		_, err = svc.Files.Update(driveFile.Id, f).AddParents(folderId).Media(file).SupportsAllDrives(true).Do()
+		err = p.call(func() error {
+			// Rewind before every attempt: a pacer-triggered retry must re-stream
+			// the whole file, not resume from wherever the previous attempt left
+			// the read cursor.
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			_, err := svc.Files.Update(driveFile.Id, f).AddParents(folderId).Media(file, mediaOption).ProgressUpdater(progressUpdater).SupportsAllDrives(true).Do()
+			return err
+		})
 	} else {
 		f := &drive.File{
 			Name:     name,
-			MimeType: mimeType,
+			MimeType: targetMimeType,
 			Parents:  []string{folderId},
 		}
-		_, err = svc.Files.Create(f).Media(file).SupportsAllDrives(true).Do()
+		err = p.call(func() error {
+			// Rewind before every attempt: a pacer-triggered retry must re-stream
+			// the whole file, not resume from wherever the previous attempt left
+			// the read cursor.
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			_, err := svc.Files.Create(f).Media(file, mediaOption).ProgressUpdater(progressUpdater).SupportsAllDrives(true).Do()
+			return err
+		})
 	}
 
 	if err != nil {
@@ -73,6 +153,91 @@ func uploadToDrive(svc *drive.Service, filename string, folderId string, driveFi
 	return nil
 }
 
+// googleDocMimeTypeByExtension maps common source file extensions to the
+// native Google Workspace MIME type Drive should convert them into when
+// importAsGoogleDocInput is enabled, matching rclone's defaultExtensions set.
+var googleDocMimeTypeByExtension = map[string]string{
+	".docx": "application/vnd.google-apps.document",
+	".doc":  "application/vnd.google-apps.document",
+	".md":   "application/vnd.google-apps.document",
+	".txt":  "application/vnd.google-apps.document",
+	".xlsx": "application/vnd.google-apps.spreadsheet",
+	".xls":  "application/vnd.google-apps.spreadsheet",
+	".pptx": "application/vnd.google-apps.presentation",
+	".ppt":  "application/vnd.google-apps.presentation",
+	".svg":  "application/vnd.google-apps.drawing",
+}
+
+// defaultGoogleDocMimeType is used when importAsGoogleDocInput is enabled but
+// the source file's extension has no entry in googleDocMimeTypeByExtension.
+const defaultGoogleDocMimeType = "application/vnd.google-apps.document"
+
+// googleDocMimeTypeForFile resolves the target application/vnd.google-apps.*
+// MIME type for filename: exportMimeType wins when set, otherwise it's
+// derived from the file's extension, falling back to defaultGoogleDocMimeType.
+func googleDocMimeTypeForFile(filename string, exportMimeType string) string {
+	if exportMimeType != "" {
+		return exportMimeType
+	}
+
+	if mimeType, ok := googleDocMimeTypeByExtension[strings.ToLower(filepath.Ext(filename))]; ok {
+		return mimeType
+	}
+
+	return defaultGoogleDocMimeType
+}
+
+// fileUnchangedOnDrive reports whether the local file at filename matches
+// driveFile's size and MD5 checksum, meaning it can be safely skipped.
+func fileUnchangedOnDrive(filename string, fi os.FileInfo, driveFile *drive.File) (bool, error) {
+	if driveFile.Md5Checksum == "" || fi.Size() != driveFile.Size {
+		return false, nil
+	}
+
+	localMd5, err := md5Checksum(filename)
+	if err != nil {
+		return false, err
+	}
+
+	return localMd5 == driveFile.Md5Checksum, nil
+}
+
+// md5Checksum returns the hex-encoded MD5 checksum of the file at filename.
+func md5Checksum(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("opening file with filename: %s failed with error: %w", filename, err)
+	}
+	defer file.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("computing md5 checksum of %s failed with error: %w", filename, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newUploadProgressUpdater returns a googleapi.ProgressUpdater that logs the
+// upload progress of filename via githubactions.Infof at ~5% intervals.
+func newUploadProgressUpdater(filename string) googleapi.ProgressUpdater {
+	lastReportedPercent := -1
+	return func(current, total int64) {
+		if total <= 0 {
+			return
+		}
+
+		percent := int(current * 100 / total)
+		percent -= percent % 5
+		if percent == lastReportedPercent {
+			return
+		}
+
+		lastReportedPercent = percent
+		githubactions.Infof("uploading %s: %d%%", filename, percent)
+	}
+}
+
 func main() {
 	// get filename argument from action input
 	filename := githubactions.GetInput(filenameInput)
@@ -133,82 +298,290 @@ func main() {
 	// get filename prefix
 	filenamePrefix := githubactions.GetInput(namePrefixInput)
 
-	// get base64 encoded credentials argument from action input
-	credentials := githubactions.GetInput(credentialsInput)
-	if credentials == "" {
-		missingInput(credentialsInput)
+	// get shared drive (Team Drive) id argument from action input
+	driveId := githubactions.GetInput(driveIdInput)
+
+	// get resumable upload chunk size argument from action input
+	chunkSize := defaultChunkSize
+	if chunkSizeRaw := githubactions.GetInput(chunkSizeInput); chunkSizeRaw != "" {
+		parsedChunkSize, err := strconv.Atoi(chunkSizeRaw)
+		if err != nil {
+			githubactions.Fatalf(fmt.Sprintf("invalid %s: %s", chunkSizeInput, err))
+		}
+		chunkSize = parsedChunkSize
+	}
+	if chunkSize <= 0 {
+		githubactions.Fatalf(fmt.Sprintf("invalid %s: must be > 0, got %d", chunkSizeInput, chunkSize))
 	}
 
-	// add base64 encoded credentials argument to mask
-	githubactions.AddMask(credentials)
+	// get pacer maxRetries argument from action input
+	maxRetries := defaultMaxRetries
+	if maxRetriesRaw := githubactions.GetInput(maxRetriesInput); maxRetriesRaw != "" {
+		parsedMaxRetries, err := strconv.Atoi(maxRetriesRaw)
+		if err != nil {
+			githubactions.Fatalf(fmt.Sprintf("invalid %s: %s", maxRetriesInput, err))
+		}
+		maxRetries = parsedMaxRetries
+	}
+	if maxRetries < 0 {
+		githubactions.Fatalf(fmt.Sprintf("invalid %s: must be >= 0, got %d", maxRetriesInput, maxRetries))
+	}
 
-	// decode credentials to []byte
-	decodedCredentials, err := base64.StdEncoding.DecodeString(credentials)
-	if err != nil {
-		githubactions.Fatalf(fmt.Sprintf("base64 decoding of 'credentials' failed with error: %v", err))
+	// get pacer minSleep argument from action input
+	minSleep := defaultMinSleep
+	if minSleepRaw := githubactions.GetInput(minSleepInput); minSleepRaw != "" {
+		parsedMinSleep, err := time.ParseDuration(minSleepRaw)
+		if err != nil {
+			githubactions.Fatalf(fmt.Sprintf("invalid %s: %s", minSleepInput, err))
+		}
+		minSleep = parsedMinSleep
 	}
 
-	// add decoded credentials argument to mask
-	creds := strings.TrimSuffix(string(decodedCredentials), "\n")
-	githubactions.AddMask(creds)
+	p := newPacer(minSleep, pacerMaxSleep, maxRetries)
+
+	// get skipIfUnchanged flag
+	var skipIfUnchangedFlag bool
+	skipIfUnchanged := githubactions.GetInput(skipIfUnchangedInput)
+	if skipIfUnchanged == "" {
+		githubactions.Infof("%s is disabled.", skipIfUnchangedInput)
+		skipIfUnchangedFlag = false
+	} else {
+		skipIfUnchangedFlag, _ = strconv.ParseBool(skipIfUnchanged)
+	}
+
+	// get worker pool concurrency argument from action input
+	concurrency := defaultConcurrency
+	if concurrencyRaw := githubactions.GetInput(concurrencyInput); concurrencyRaw != "" {
+		parsedConcurrency, err := strconv.Atoi(concurrencyRaw)
+		if err != nil {
+			githubactions.Fatalf(fmt.Sprintf("invalid %s: %s", concurrencyInput, err))
+		}
+		concurrency = parsedConcurrency
+	}
+	if concurrency < 1 {
+		githubactions.Fatalf(fmt.Sprintf("invalid %s: must be >= 1, got %d", concurrencyInput, concurrency))
+	}
+
+	// get no-dir-cache escape hatch flag
+	var noDirCacheFlag bool
+	noDirCache := githubactions.GetInput(noDirCacheInput)
+	if noDirCache == "" {
+		githubactions.Infof("%s is disabled.", noDirCacheInput)
+		noDirCacheFlag = false
+	} else {
+		noDirCacheFlag, _ = strconv.ParseBool(noDirCache)
+	}
+
+	// get importAsGoogleDoc flag
+	var importAsGoogleDocFlag bool
+	importAsGoogleDoc := githubactions.GetInput(importAsGoogleDocInput)
+	if importAsGoogleDoc == "" {
+		githubactions.Infof("%s is disabled.", importAsGoogleDocInput)
+		importAsGoogleDocFlag = false
+	} else {
+		importAsGoogleDocFlag, _ = strconv.ParseBool(importAsGoogleDoc)
+	}
+
+	// get exportMimeType argument from action input
+	exportMimeType := githubactions.GetInput(exportMimeTypeInput)
+
+	// get OAuth2 user-token arguments from action input
+	oauthClientId := githubactions.GetInput(oauthClientIdInput)
+	oauthClientSecret := githubactions.GetInput(oauthClientSecretInput)
+	oauthRefreshToken := githubactions.GetInput(oauthRefreshTokenInput)
 
-	// instantiating a new drive service
 	ctx := context.Background()
-	svc, err := drive.NewService(ctx, option.WithCredentialsJSON([]byte(creds)))
+
+	var clientOption option.ClientOption
+	if oauthClientId != "" || oauthClientSecret != "" || oauthRefreshToken != "" {
+		if oauthClientId == "" || oauthClientSecret == "" || oauthRefreshToken == "" {
+			githubactions.Fatalf("%s, %s and %s must all be set to use OAuth2 user-token authentication", oauthClientIdInput, oauthClientSecretInput, oauthRefreshTokenInput)
+		}
+
+		// add OAuth2 credentials to mask
+		githubactions.AddMask(oauthClientSecret)
+		githubactions.AddMask(oauthRefreshToken)
+
+		oauthConfig := &oauth2.Config{
+			ClientID:     oauthClientId,
+			ClientSecret: oauthClientSecret,
+			Endpoint:     google.Endpoint,
+			Scopes:       []string{drive.DriveScope},
+		}
+
+		httpClient := oauthConfig.Client(ctx, &oauth2.Token{RefreshToken: oauthRefreshToken})
+		clientOption = option.WithHTTPClient(httpClient)
+	} else {
+		// get base64 encoded credentials argument from action input
+		credentials := githubactions.GetInput(credentialsInput)
+		if credentials == "" {
+			missingInput(credentialsInput)
+		}
+
+		// add base64 encoded credentials argument to mask
+		githubactions.AddMask(credentials)
+
+		// decode credentials to []byte
+		decodedCredentials, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			githubactions.Fatalf(fmt.Sprintf("base64 decoding of 'credentials' failed with error: %v", err))
+		}
+
+		// add decoded credentials argument to mask
+		creds := strings.TrimSuffix(string(decodedCredentials), "\n")
+		githubactions.AddMask(creds)
+
+		clientOption = option.WithCredentialsJSON([]byte(creds))
+	}
+
+	// instantiating a new drive service
+	svc, err := drive.NewService(ctx, clientOption)
 	if err != nil {
 		githubactions.Errorf("creating drive client failed with error: %s", err)
 	}
 
-	useSourceFilename := len(files) > 1
+	cfg := &uploadConfig{
+		svc:                                 svc,
+		pacer:                               p,
+		dirCache:                            newDirCache(noDirCacheFlag),
+		originalFolderId:                    folderId,
+		name:                                name,
+		mimeType:                            mimeType,
+		overwriteFlag:                       overwriteFlag,
+		chunkSize:                           chunkSize,
+		driveId:                             driveId,
+		skipIfUnchanged:                     skipIfUnchangedFlag,
+		useCompleteSourceFilenameAsNameFlag: useCompleteSourceFilenameAsNameFlag,
+		useSourceFilename:                   len(files) > 1,
+		mirrorDirectoryStructureFlag:        mirrorDirectoryStructureFlag,
+		filenamePrefix:                      filenamePrefix,
+		importAsGoogleDoc:                   importAsGoogleDocFlag,
+		exportMimeType:                      exportMimeType,
+	}
 
-	// Save the folderId because it might get overwritten by createDriveDirectory
-	originalFolderId := folderId
-	for _, file := range files {
-		folderId = originalFolderId
+	g, gctx := errgroup.WithContext(ctx)
+	fileCh := make(chan string)
 
-		githubactions.Infof("Processing file %s", file)
-		if mirrorDirectoryStructureFlag {
-			directoryStructure := strings.Split(filepath.Dir(file), string(os.PathSeparator))
-			githubactions.Infof("Mirroring directory structure: %v", directoryStructure)
-			for _, dir := range directoryStructure {
-				folderId, err = createDriveDirectory(svc, folderId, dir)
-				if err != nil {
-					githubactions.Fatalf("creating directory %s failed with error: %s", dir, err)
-				}
+	go func() {
+		defer close(fileCh)
+		for _, file := range files {
+			select {
+			case fileCh <- file:
+			case <-gctx.Done():
+				return
 			}
 		}
+	}()
 
-		targetName := name
-		if useCompleteSourceFilenameAsNameFlag {
-			targetName = file
-		} else if useSourceFilename || name == "" {
-			targetName = filepath.Base(file)
-		}
+	for i := 0; i < concurrency; i++ {
+		g.Go(func() error {
+			for file := range fileCh {
+				if err := uploadOneFile(cfg, file); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
 
-		if targetName == "" {
-			githubactions.Fatalf("could not discover target file name")
-		}
+	if err := g.Wait(); err != nil {
+		githubactions.Fatalf("uploading file failed with error: %s", err)
+	}
+}
 
-		if filenamePrefix != "" {
-			targetName = filenamePrefix + targetName
-		}
+// uploadConfig holds the per-run settings shared by all upload workers.
+type uploadConfig struct {
+	svc                                 *drive.Service
+	pacer                               *pacer
+	dirCache                            *dirCache
+	originalFolderId                    string
+	name                                string
+	mimeType                            string
+	overwriteFlag                       bool
+	chunkSize                           int
+	driveId                             string
+	skipIfUnchanged                     bool
+	useCompleteSourceFilenameAsNameFlag bool
+	useSourceFilename                   bool
+	mirrorDirectoryStructureFlag        bool
+	filenamePrefix                      string
+	importAsGoogleDoc                   bool
+	exportMimeType                      string
+}
 
-		if err := uploadFile(svc, file, folderId, targetName, mimeType, overwriteFlag); err != nil {
-			githubactions.Fatalf("uploading file failed with error: %s", err)
+// uploadOneFile mirrors the directory structure for file (if enabled),
+// resolves its target name and uploads it, using the shared config cfg.
+func uploadOneFile(cfg *uploadConfig, file string) error {
+	folderId := cfg.originalFolderId
+
+	githubactions.Infof("Processing file %s", file)
+	if cfg.mirrorDirectoryStructureFlag {
+		directoryStructure := strings.Split(filepath.Dir(file), string(os.PathSeparator))
+		githubactions.Infof("Mirroring directory structure: %v", directoryStructure)
+		for _, dir := range directoryStructure {
+			var err error
+			folderId, err = createDriveDirectory(cfg.pacer, cfg.svc, cfg.dirCache, folderId, dir, cfg.driveId)
+			if err != nil {
+				return fmt.Errorf("creating directory %s failed with error: %w", dir, err)
+			}
 		}
 	}
+
+	targetName := cfg.name
+	if cfg.useCompleteSourceFilenameAsNameFlag {
+		targetName = file
+	} else if cfg.useSourceFilename || cfg.name == "" {
+		targetName = filepath.Base(file)
+	}
+
+	if targetName == "" {
+		return fmt.Errorf("could not discover target file name")
+	}
+
+	if cfg.filenamePrefix != "" {
+		targetName = cfg.filenamePrefix + targetName
+	}
+
+	return uploadFile(cfg.pacer, cfg.svc, file, folderId, targetName, cfg.mimeType, cfg.overwriteFlag, cfg.chunkSize, cfg.driveId, cfg.skipIfUnchanged, cfg.importAsGoogleDoc, cfg.exportMimeType)
 }
 
-func createDriveDirectory(svc *drive.Service, folderId string, name string) (string, error) {
+func createDriveDirectory(p *pacer, svc *drive.Service, dc *dirCache, folderId string, name string, driveId string) (string, error) {
+	// The mutex is held across the cache lookup and the list/create round-trips
+	// below, so two workers can never both decide to create the same folder.
+	// Folder lookups are therefore serialized; file uploads still run in
+	// parallel.
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	key := dirCacheKey(folderId, name)
+	if !dc.disabled {
+		cachedFolderId, ok := dc.entries[key]
+		if ok {
+			githubactions.Debugf("directory cache hit for folder %s.", name)
+			return cachedFolderId, nil
+		}
+	}
+
 	githubactions.Infof("Checking for existing folder %s", name)
-	r, err := svc.Files.
+	call := svc.Files.
 		List().
 		Fields("files(name,id,mimeType,parents)").
-		Q("name='" + name + "'" + " and mimeType='application/vnd.google-apps.folder'").
+		Q("name='" + driveQuoteString(name) + "'" + " and mimeType='application/vnd.google-apps.folder'").
 		IncludeItemsFromAllDrives(true).
-		Corpora("allDrives").
-		SupportsAllDrives(true).
-		Do()
+		SupportsAllDrives(true)
+
+	if driveId != "" {
+		call = call.Corpora("drive").DriveId(driveId)
+	} else {
+		call = call.Corpora("allDrives")
+	}
+
+	var r *drive.FileList
+	err := p.call(func() error {
+		var err error
+		r, err = call.Do()
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("unable to retrieve files: %w", err)
 	}
@@ -233,34 +606,56 @@ func createDriveDirectory(svc *drive.Service, folderId string, name string) (str
 			Parents:  []string{folderId},
 		}
 
-		d, err := svc.Files.Create(f).Fields("id").SupportsAllDrives(true).Do()
+		var d *drive.File
+		err := p.call(func() error {
+			var err error
+			d, err = svc.Files.Create(f).Fields("id").SupportsAllDrives(true).Do()
+			return err
+		})
 		if err != nil {
+			// Nothing has been written into the cache for key yet at this point,
+			// so there's nothing of this call's own to invalidate here.
 			return "", fmt.Errorf("creating folder failed with error: %w", err)
 		}
 
 		nextFolderId = d.Id
 	}
 
+	if !dc.disabled {
+		dc.entries[key] = nextFolderId
+	}
+
 	return nextFolderId, nil
 }
 
-func uploadFile(svc *drive.Service, filename string, folderId string, name string, mimeType string, overwriteFlag bool) error {
+func uploadFile(p *pacer, svc *drive.Service, filename string, folderId string, name string, mimeType string, overwriteFlag bool, chunkSize int, driveId string, skipIfUnchanged bool, importAsGoogleDoc bool, exportMimeType string) error {
 	githubactions.Infof("target file name: %s", name)
 
 	if !overwriteFlag {
-		return uploadToDrive(svc, filename, folderId, nil, name, mimeType)
+		return uploadToDrive(p, svc, filename, folderId, nil, name, mimeType, chunkSize, skipIfUnchanged, importAsGoogleDoc, exportMimeType)
 	}
 
 	// overwrite flag is true
-	r, err := svc.Files.
+	call := svc.Files.
 		List().
-		Fields("files(name,id,mimeType,parents)").
-		Q("name='" + name + "'").
+		Fields("files(name,id,mimeType,parents,md5Checksum,size)").
+		Q("name='" + driveQuoteString(name) + "'").
 		IncludeItemsFromAllDrives(true).
-		Corpora("allDrives").
 		SupportsAllDrives(true).
-		IncludeTeamDriveItems(true).
-		Do()
+		IncludeTeamDriveItems(true)
+
+	if driveId != "" {
+		call = call.Corpora("drive").DriveId(driveId)
+	} else {
+		call = call.Corpora("allDrives")
+	}
+
+	var r *drive.FileList
+	err := p.call(func() error {
+		var err error
+		r, err = call.Do()
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("unable to retrieve files: %w", err)
 	}
@@ -287,13 +682,22 @@ func uploadFile(svc *drive.Service, filename string, folderId string, name strin
 
 	if currentFile == nil {
 		githubactions.Infof("No similar files found. Creating a new file")
-		return uploadToDrive(svc, filename, folderId, nil, name, mimeType)
+		return uploadToDrive(p, svc, filename, folderId, nil, name, mimeType, chunkSize, skipIfUnchanged, importAsGoogleDoc, exportMimeType)
 	}
 
 	githubactions.Infof("Overwriting file: %s (%s)", currentFile.Name, currentFile.Id)
-	return uploadToDrive(svc, filename, folderId, currentFile, name, mimeType)
+	return uploadToDrive(p, svc, filename, folderId, currentFile, name, mimeType, chunkSize, skipIfUnchanged, importAsGoogleDoc, exportMimeType)
 }
 
 func missingInput(inputName string) {
 	githubactions.Fatalf(fmt.Sprintf("missing input '%v'", inputName))
 }
+
+// driveQuoteString escapes s for use inside a Drive API query string literal.
+// Per Drive's query grammar, backslashes and single quotes must be escaped,
+// otherwise names containing them break the query or silently match nothing.
+func driveQuoteString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return s
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestDriveQuoteString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "report.pdf", want: "report.pdf"},
+		{name: "apostrophe", in: "Q1 20'25 report.pdf", want: `Q1 20\'25 report.pdf`},
+		{name: "backslash", in: `notes\draft.txt`, want: `notes\\draft.txt`},
+		{name: "backslash and apostrophe", in: `it's a \test\`, want: `it\'s a \\test\\`},
+		{name: "unicode", in: "rapport d'été.docx", want: `rapport d\'été.docx`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := driveQuoteString(tt.in); got != tt.want {
+				t.Errorf("driveQuoteString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sethvargo/go-githubactions"
+	"google.golang.org/api/googleapi"
+)
+
+// pacer paces outgoing Drive API calls with exponential backoff, mirroring
+// rclone's lib/pacer: each retryable error doubles the sleep interval, up to
+// maxSleep, and each success halves it back down, down to minSleep.
+type pacer struct {
+	mu         sync.Mutex
+	minSleep   time.Duration
+	maxSleep   time.Duration
+	sleepTime  time.Duration
+	maxRetries int
+}
+
+// pacerDecay is the multiplicative factor applied to the sleep interval on
+// retry (and its inverse on success).
+const pacerDecay = 2
+
+func newPacer(minSleep time.Duration, maxSleep time.Duration, maxRetries int) *pacer {
+	return &pacer{
+		minSleep:   minSleep,
+		maxSleep:   maxSleep,
+		sleepTime:  minSleep,
+		maxRetries: maxRetries,
+	}
+}
+
+// call runs fn, retrying on retryable Drive API errors according to the
+// pacer's backoff schedule, up to maxRetries times.
+func (p *pacer) call(fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		err = fn()
+		if err == nil {
+			p.decreaseSleep()
+			return nil
+		}
+
+		if !isRetryableDriveError(err) {
+			return err
+		}
+
+		if attempt == p.maxRetries {
+			break
+		}
+
+		sleep := p.increaseSleep()
+		githubactions.Debugf("retryable drive error: %s, retrying in %s (attempt %d/%d)", err, sleep, attempt+1, p.maxRetries)
+		time.Sleep(sleep)
+	}
+
+	return err
+}
+
+func (p *pacer) increaseSleep() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sleepTime *= pacerDecay
+	if p.sleepTime > p.maxSleep {
+		p.sleepTime = p.maxSleep
+	}
+
+	return p.sleepTime
+}
+
+func (p *pacer) decreaseSleep() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sleepTime /= pacerDecay
+	if p.sleepTime < p.minSleep {
+		p.sleepTime = p.minSleep
+	}
+}
+
+// isRetryableDriveError reports whether err is a Drive API error worth
+// retrying: rate limiting (403/429) or a server-side (5xx) failure.
+func isRetryableDriveError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.Code {
+	case 403, 429:
+		return true
+	default:
+		return apiErr.Code >= 500
+	}
+}